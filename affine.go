@@ -0,0 +1,298 @@
+package resize
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"runtime"
+)
+
+// Affine is a 2-D affine transformation matrix that maps a source point
+// (x, y) to a destination point (x', y'); Transform uses its inverse to
+// find the source position of each destination pixel:
+//
+//	x' = m[0]*x + m[1]*y + m[2]
+//	y' = m[3]*x + m[4]*y + m[5]
+//
+// The zero value is not a valid transform; use Identity or one of the
+// constructors below.
+type Affine [6]float64
+
+// Identity is the affine transform that maps every point to itself.
+var Identity = Affine{1, 0, 0, 0, 1, 0}
+
+// Translate returns an affine transform that shifts points by (tx, ty).
+func Translate(tx, ty float64) Affine {
+	return Affine{1, 0, tx, 0, 1, ty}
+}
+
+// Scale returns an affine transform that scales points by (sx, sy) about
+// the origin.
+func Scale(sx, sy float64) Affine {
+	return Affine{sx, 0, 0, 0, sy, 0}
+}
+
+// Rotate returns an affine transform that rotates points by angle radians,
+// counter-clockwise, about the origin.
+func Rotate(angle float64) Affine {
+	sin, cos := math.Sincos(angle)
+	return Affine{cos, -sin, 0, sin, cos, 0}
+}
+
+// Shear returns an affine transform that shears points by shx along the x
+// axis and shy along the y axis.
+func Shear(shx, shy float64) Affine {
+	return Affine{1, shx, 0, shy, 1, 0}
+}
+
+// Mul returns the affine transform that first applies a, then m.
+func (m Affine) Mul(a Affine) Affine {
+	return Affine{
+		m[0]*a[0] + m[1]*a[3], m[0]*a[1] + m[1]*a[4], m[0]*a[2] + m[1]*a[5] + m[2],
+		m[3]*a[0] + m[4]*a[3], m[3]*a[1] + m[4]*a[4], m[3]*a[2] + m[4]*a[5] + m[5],
+	}
+}
+
+// Apply maps (x, y) through m.
+func (m Affine) Apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[1]*y + m[2], m[3]*x + m[4]*y + m[5]
+}
+
+// Invert returns the inverse of m and reports whether m is invertible.
+func (m Affine) Invert() (Affine, bool) {
+	a, b, c := m[0], m[1], m[2]
+	d, e, f := m[3], m[4], m[5]
+	det := a*e - b*d
+	if det == 0 {
+		return Affine{}, false
+	}
+	ia, ib := e/det, -b/det
+	id, ie := -d/det, a/det
+	return Affine{ia, ib, -(ia*c + ib*f), id, ie, -(id*c + ie*f)}, true
+}
+
+// BoundaryMode controls how Transform handles source positions that fall
+// outside an image's bounds.
+type BoundaryMode int
+
+const (
+	// BoundaryClamp clamps out-of-bounds source positions to the
+	// nearest edge pixel. It is the zero value.
+	BoundaryClamp BoundaryMode = iota
+	// BoundaryWrap wraps out-of-bounds source positions around the
+	// image, as if it tiled.
+	BoundaryWrap
+	// BoundaryTransparent drops out-of-bounds taps entirely; pixels
+	// with no in-bounds taps are filled with TransformOptions.Background.
+	BoundaryTransparent
+)
+
+// TransformOptions customizes Transform.
+type TransformOptions struct {
+	// Background fills destination pixels whose source position (and
+	// every tap around it) falls outside src's bounds under
+	// BoundaryTransparent. The zero value is fully transparent.
+	Background color.Color
+	// Boundary selects how out-of-bounds source positions are handled.
+	// The zero value is BoundaryClamp.
+	Boundary BoundaryMode
+}
+
+// Transform maps every pixel of dst to a position in src via the inverse
+// of m and writes the resampled value using interp's kernel. Unlike
+// Resize, which only scales along the image axes with a separable two-pass
+// filter, Transform resamples each destination pixel independently against
+// a taps x taps neighborhood, so it also supports rotation and shear.
+func Transform(dst draw.Image, src image.Image, m Affine, interp InterpolationFunction, opts TransformOptions) error {
+	inv, ok := m.Invert()
+	if !ok {
+		return errors.New("resize: affine transform is not invertible")
+	}
+
+	taps, kernel := interp.kernel()
+	bg := opts.Background
+	if bg == nil {
+		bg = color.Transparent
+	}
+
+	db := dst.Bounds()
+	sb := src.Bounds()
+	sample := sampler(src)
+	write := writer(dst)
+	br, bgg, bb, ba := bg.RGBA()
+	bgSample := color.RGBA64{R: uint16(br), G: uint16(bgg), B: uint16(bb), A: uint16(ba)}
+
+	cpus := runtime.NumCPU()
+	rows := db.Dy()
+	if rows < cpus {
+		cpus = rows
+	}
+	if cpus < 1 {
+		cpus = 1
+	}
+
+	done := make(chan struct{}, cpus)
+	for p := 0; p < cpus; p++ {
+		go func(p int) {
+			y0 := db.Min.Y + p*rows/cpus
+			y1 := db.Min.Y + (p+1)*rows/cpus
+			transformRows(write, sample, sb, inv, taps, kernel, opts.Boundary, bgSample, db.Min.X, db.Max.X, y0, y1)
+			done <- struct{}{}
+		}(p)
+	}
+	for p := 0; p < cpus; p++ {
+		<-done
+	}
+	return nil
+}
+
+// sampler returns a pixel reader specialized on src's concrete type, so
+// Transform avoids the color-model conversion a generic src.At(x,y).RGBA()
+// does on every tap, the same way resize() dispatches on its input type.
+func sampler(src image.Image) func(x, y int) (r, g, b, a uint32) {
+	switch s := src.(type) {
+	case *image.RGBA:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return s.RGBAAt(x, y).RGBA()
+		}
+	case *image.RGBA64:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			c := s.RGBA64At(x, y)
+			return uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+		}
+	case *image.Gray:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			v := uint32(s.GrayAt(x, y).Y) * 0x101
+			return v, v, v, 0xffff
+		}
+	case *image.Gray16:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			v := uint32(s.Gray16At(x, y).Y)
+			return v, v, v, 0xffff
+		}
+	case *image.YCbCr:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			yv := s.Y[s.YOffset(x, y)]
+			cb := s.Cb[s.COffset(x, y)]
+			cr := s.Cr[s.COffset(x, y)]
+			r8, g8, b8 := color.YCbCrToRGB(yv, cb, cr)
+			return uint32(r8) * 0x101, uint32(g8) * 0x101, uint32(b8) * 0x101, 0xffff
+		}
+	default:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return src.At(x, y).RGBA()
+		}
+	}
+}
+
+// writer returns a pixel writer specialized on dst's concrete type when
+// it's one the package knows, falling back to the generic draw.Image.Set.
+func writer(dst draw.Image) func(x, y int, c color.RGBA64) {
+	switch d := dst.(type) {
+	case *image.RGBA:
+		return func(x, y int, c color.RGBA64) {
+			d.SetRGBA(x, y, color.RGBA{R: uint8(c.R >> 8), G: uint8(c.G >> 8), B: uint8(c.B >> 8), A: uint8(c.A >> 8)})
+		}
+	case *image.RGBA64:
+		return func(x, y int, c color.RGBA64) { d.SetRGBA64(x, y, c) }
+	case *image.Gray:
+		return func(x, y int, c color.RGBA64) { d.SetGray(x, y, color.Gray{Y: uint8(c.R >> 8)}) }
+	case *image.Gray16:
+		return func(x, y int, c color.RGBA64) { d.SetGray16(x, y, color.Gray16{Y: c.R}) }
+	default:
+		return func(x, y int, c color.RGBA64) { dst.Set(x, y, c) }
+	}
+}
+
+func transformRows(write func(x, y int, c color.RGBA64), sample func(x, y int) (r, g, b, a uint32), sb image.Rectangle, inv Affine, taps int, kernel func(float64) float64, boundary BoundaryMode, bg color.RGBA64, x0, x1, y0, y1 int) {
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			sx, sy := inv.Apply(float64(x)+0.5, float64(y)+0.5)
+			sx -= 0.5
+			sy -= 0.5
+			ix, iy := int(math.Floor(sx)), int(math.Floor(sy))
+
+			var r, g, b, a, wsum float64
+			for j := 0; j < taps; j++ {
+				ty := iy - taps/2 + 1 + j
+				wy := kernel(sy - float64(ty))
+				if wy == 0 {
+					continue
+				}
+				for i := 0; i < taps; i++ {
+					tx := ix - taps/2 + 1 + i
+					wx := kernel(sx - float64(tx))
+					w := wx * wy
+					if w == 0 {
+						continue
+					}
+					px, py, ok := resolveBoundary(tx, ty, sb, boundary)
+					if !ok {
+						continue
+					}
+					cr, cg, cb, ca := sample(px, py)
+					r += float64(cr) * w
+					g += float64(cg) * w
+					b += float64(cb) * w
+					a += float64(ca) * w
+					wsum += w
+				}
+			}
+
+			if wsum == 0 {
+				write(x, y, bg)
+				continue
+			}
+			write(x, y, color.RGBA64{
+				R: clampUint16(r / wsum),
+				G: clampUint16(g / wsum),
+				B: clampUint16(b / wsum),
+				A: clampUint16(a / wsum),
+			})
+		}
+	}
+}
+
+func resolveBoundary(x, y int, b image.Rectangle, mode BoundaryMode) (int, int, bool) {
+	if (image.Point{X: x, Y: y}).In(b) {
+		return x, y, true
+	}
+	switch mode {
+	case BoundaryWrap:
+		return b.Min.X + wrapIndex(x-b.Min.X, b.Dx()), b.Min.Y + wrapIndex(y-b.Min.Y, b.Dy()), true
+	case BoundaryTransparent:
+		return 0, 0, false
+	default: // BoundaryClamp
+		if x < b.Min.X {
+			x = b.Min.X
+		} else if x >= b.Max.X {
+			x = b.Max.X - 1
+		}
+		if y < b.Min.Y {
+			y = b.Min.Y
+		} else if y >= b.Max.Y {
+			y = b.Max.Y - 1
+		}
+		return x, y, true
+	}
+}
+
+func wrapIndex(i, n int) int {
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
+
+func clampUint16(v float64) uint16 {
+	if v < 0 {
+		return 0
+	}
+	if v > 0xffff {
+		return 0xffff
+	}
+	return uint16(v + 0.5)
+}
@@ -0,0 +1,62 @@
+package resize
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestAffineInvertRoundTrip(t *testing.T) {
+	cases := []Affine{
+		Identity,
+		Translate(3, -5),
+		Scale(2, 0.5),
+		Rotate(math.Pi / 6),
+		Shear(0.3, -0.1),
+		Rotate(1.2).Mul(Scale(2, 3)).Mul(Translate(4, -2)),
+	}
+
+	for _, m := range cases {
+		inv, ok := m.Invert()
+		if !ok {
+			t.Fatalf("%v: expected invertible", m)
+		}
+		x, y := m.Apply(7, -11)
+		rx, ry := inv.Apply(x, y)
+		if math.Abs(rx-7) > 1e-9 || math.Abs(ry-(-11)) > 1e-9 {
+			t.Errorf("%v: round trip = (%v, %v), want (7, -11)", m, rx, ry)
+		}
+	}
+}
+
+func TestAffineInvertSingular(t *testing.T) {
+	m := Affine{1, 1, 0, 1, 1, 0}
+	if _, ok := m.Invert(); ok {
+		t.Fatalf("%v: expected non-invertible", m)
+	}
+}
+
+func TestTransformIdentityRGBA(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			src.SetRGBA(x, y, color.RGBA{R: uint8(x * 50), G: uint8(y * 50), B: 0, A: 255})
+		}
+	}
+
+	dst := image.NewRGBA(src.Bounds())
+	if err := Transform(dst, src, Identity, Bilinear, TransformOptions{}); err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+
+	for y := 1; y < 3; y++ {
+		for x := 1; x < 3; x++ {
+			want := src.RGBAAt(x, y)
+			got := dst.RGBAAt(x, y)
+			if got != want {
+				t.Errorf("(%d,%d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,511 @@
+package resize
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+)
+
+// Kernel2D is a 1-D convolution kernel applied separably, first
+// horizontally then vertically. Weights need not sum to 1; Convolve
+// divides each result by the sum of the weights. Taps that fall outside
+// the image read the nearest edge pixel.
+type Kernel2D struct {
+	Weights []float64
+	// Anchor is the index of Weights that lines up with the pixel being
+	// filtered. The zero value anchors on the first weight.
+	Anchor int
+}
+
+// Convolve applies k to img, first horizontally then vertically.
+// Generic access to image.Image is slow in tight loops (see resize()), so
+// Convolve dispatches on img's concrete type and filters the native pixel
+// representation directly, the same way resize() does; *image.YCbCr is
+// filtered via its Y/Cb/Cr planes so the result stays a *image.YCbCr
+// instead of silently changing type.
+func Convolve(img image.Image, k Kernel2D) image.Image {
+	switch input := img.(type) {
+	case *image.RGBA:
+		return convolveRGBA(input, k)
+	case *image.YCbCr:
+		return convolveYCbCr(input, k)
+	case *image.Gray:
+		return convolveGray(input, k)
+	case *image.Gray16:
+		return convolveGray16(input, k)
+	case *image.RGBA64:
+		return convolveRGBA64(input, k)
+	default:
+		return convolveGeneric(img, k)
+	}
+}
+
+// GaussianBlur returns a copy of img blurred with a Gaussian kernel of the
+// given standard deviation, truncated at +/-3 sigma.
+func GaussianBlur(img image.Image, sigma float64) image.Image {
+	return Convolve(img, gaussianKernel(sigma))
+}
+
+// BoxBlur returns a copy of img blurred with a (2*radius+1)-wide box kernel.
+func BoxBlur(img image.Image, radius int) image.Image {
+	return Convolve(img, boxKernel(radius))
+}
+
+// UnsharpMask sharpens img by amplifying its difference from a
+// Gaussian-blurred copy (sigma, amount). Per-channel differences no
+// larger than threshold (out of 0xffff) are left untouched, to avoid
+// amplifying noise in flat regions.
+func UnsharpMask(img image.Image, sigma, amount float64, threshold uint32) image.Image {
+	return sharpen(img, GaussianBlur(img, sigma), amount, threshold)
+}
+
+// ResizeBlur behaves like Resize but first blurs img with a Gaussian of
+// the given sigma, which helps suppress moiré on large downscales without
+// a separate GaussianBlur call. A sigma <= 0 skips the pre-blur.
+func ResizeBlur(width, height uint, img image.Image, interp InterpolationFunction, sigma float64) image.Image {
+	if sigma > 0 {
+		img = GaussianBlur(img, sigma)
+	}
+	return Resize(width, height, img, interp)
+}
+
+func gaussianKernel(sigma float64) Kernel2D {
+	if sigma <= 0 {
+		return Kernel2D{Weights: []float64{1}}
+	}
+	radius := int(math.Ceil(sigma * 3))
+	if radius < 1 {
+		radius = 1
+	}
+	weights := make([]float64, 2*radius+1)
+	var sum float64
+	for i := range weights {
+		x := float64(i - radius)
+		w := math.Exp(-(x * x) / (2 * sigma * sigma))
+		weights[i] = w
+		sum += w
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+	return Kernel2D{Weights: weights, Anchor: radius}
+}
+
+func boxKernel(radius int) Kernel2D {
+	if radius < 0 {
+		radius = 0
+	}
+	n := 2*radius + 1
+	weights := make([]float64, n)
+	w := 1 / float64(n)
+	for i := range weights {
+		weights[i] = w
+	}
+	return Kernel2D{Weights: weights, Anchor: radius}
+}
+
+// parallelRows runs fn over [0, h) split into goroutine-sized row ranges,
+// the same row-based fan-out resize() uses.
+func parallelRows(h int, fn func(y0, y1 int)) {
+	cpus := runtime.NumCPU()
+	if h < cpus {
+		cpus = h
+	}
+	if cpus < 1 {
+		cpus = 1
+	}
+	done := make(chan struct{}, cpus)
+	for p := 0; p < cpus; p++ {
+		go func(p int) {
+			fn(p*h/cpus, (p+1)*h/cpus)
+			done <- struct{}{}
+		}(p)
+	}
+	for p := 0; p < cpus; p++ {
+		<-done
+	}
+}
+
+// convolveChannel8 separably convolves an 8-bit plane accessed through
+// get/set, which index directly into the concrete image's pixel slices
+// (Pix, or the Y/Cb/Cr planes of an *image.YCbCr).
+func convolveChannel8(w, h int, k Kernel2D, get func(x, y int) byte, set func(x, y int, v byte)) {
+	temp := make([]byte, w*h)
+	convolveChannel8Pass(w, h, k, true, get, func(x, y int, v byte) { temp[y*w+x] = v })
+	convolveChannel8Pass(w, h, k, false, func(x, y int) byte { return temp[y*w+x] }, set)
+}
+
+func convolveChannel8Pass(w, h int, k Kernel2D, horizontal bool, get func(x, y int) byte, set func(x, y int, v byte)) {
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				var sum, wsum float64
+				for i, wt := range k.Weights {
+					offset := i - k.Anchor
+					sx, sy := x, y
+					if horizontal {
+						sx = clampInt(x+offset, 0, w-1)
+					} else {
+						sy = clampInt(y+offset, 0, h-1)
+					}
+					sum += float64(get(sx, sy)) * wt
+					wsum += wt
+				}
+				if wsum == 0 {
+					wsum = 1
+				}
+				v := sum / wsum
+				if v < 0 {
+					v = 0
+				} else if v > 255 {
+					v = 255
+				}
+				set(x, y, byte(v+0.5))
+			}
+		}
+	})
+}
+
+// convolveChannel16 is convolveChannel8 for 16-bit-precision planes.
+func convolveChannel16(w, h int, k Kernel2D, get func(x, y int) uint16, set func(x, y int, v uint16)) {
+	temp := make([]uint16, w*h)
+	convolveChannel16Pass(w, h, k, true, get, func(x, y int, v uint16) { temp[y*w+x] = v })
+	convolveChannel16Pass(w, h, k, false, func(x, y int) uint16 { return temp[y*w+x] }, set)
+}
+
+func convolveChannel16Pass(w, h int, k Kernel2D, horizontal bool, get func(x, y int) uint16, set func(x, y int, v uint16)) {
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < w; x++ {
+				var sum, wsum float64
+				for i, wt := range k.Weights {
+					offset := i - k.Anchor
+					sx, sy := x, y
+					if horizontal {
+						sx = clampInt(x+offset, 0, w-1)
+					} else {
+						sy = clampInt(y+offset, 0, h-1)
+					}
+					sum += float64(get(sx, sy)) * wt
+					wsum += wt
+				}
+				if wsum == 0 {
+					wsum = 1
+				}
+				set(x, y, clampUint16(sum/wsum))
+			}
+		}
+	})
+}
+
+func convolveRGBA(img *image.RGBA, k Kernel2D) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewRGBA(b)
+	for c := 0; c < 4; c++ {
+		c := c
+		convolveChannel8(w, h, k,
+			func(x, y int) byte { return img.Pix[img.PixOffset(b.Min.X+x, b.Min.Y+y)+c] },
+			func(x, y int, v byte) { dst.Pix[dst.PixOffset(b.Min.X+x, b.Min.Y+y)+c] = v },
+		)
+	}
+	return dst
+}
+
+func convolveGray(img *image.Gray, k Kernel2D) *image.Gray {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewGray(b)
+	convolveChannel8(w, h, k,
+		func(x, y int) byte { return img.Pix[img.PixOffset(b.Min.X+x, b.Min.Y+y)] },
+		func(x, y int, v byte) { dst.Pix[dst.PixOffset(b.Min.X+x, b.Min.Y+y)] = v },
+	)
+	return dst
+}
+
+func convolveGray16(img *image.Gray16, k Kernel2D) *image.Gray16 {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewGray16(b)
+	convolveChannel16(w, h, k,
+		func(x, y int) uint16 { return img.Gray16At(b.Min.X+x, b.Min.Y+y).Y },
+		func(x, y int, v uint16) { dst.SetGray16(b.Min.X+x, b.Min.Y+y, color.Gray16{Y: v}) },
+	)
+	return dst
+}
+
+func convolveRGBA64(img *image.RGBA64, k Kernel2D) *image.RGBA64 {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewRGBA64(b)
+	for c := 0; c < 4; c++ {
+		c := c
+		convolveChannel16(w, h, k,
+			func(x, y int) uint16 { return rgba64Chan(img.RGBA64At(b.Min.X+x, b.Min.Y+y), c) },
+			func(x, y int, v uint16) {
+				p := dst.RGBA64At(b.Min.X+x, b.Min.Y+y)
+				setRGBA64Chan(&p, c, v)
+				dst.SetRGBA64(b.Min.X+x, b.Min.Y+y, p)
+			},
+		)
+	}
+	return dst
+}
+
+// convolveYCbCr filters the Y, Cb and Cr planes of a decoded JPEG-style
+// image independently, so the result keeps img's SubsampleRatio and
+// concrete type. It indexes through YOffset/COffset rather than assuming
+// the planes are tightly packed, since a SubImage'd *image.YCbCr keeps its
+// parent's stride while only exposing a narrower Rect.
+func convolveYCbCr(img *image.YCbCr, k Kernel2D) *image.YCbCr {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewYCbCr(b, img.SubsampleRatio)
+	convolveChannel8(w, h, k,
+		func(x, y int) byte { return img.Y[img.YOffset(b.Min.X+x, b.Min.Y+y)] },
+		func(x, y int, v byte) { dst.Y[dst.YOffset(b.Min.X+x, b.Min.Y+y)] = v },
+	)
+	convolveChannel8(w, h, k,
+		func(x, y int) byte { return img.Cb[img.COffset(b.Min.X+x, b.Min.Y+y)] },
+		func(x, y int, v byte) { dst.Cb[dst.COffset(b.Min.X+x, b.Min.Y+y)] = v },
+	)
+	convolveChannel8(w, h, k,
+		func(x, y int) byte { return img.Cr[img.COffset(b.Min.X+x, b.Min.Y+y)] },
+		func(x, y int, v byte) { dst.Cr[dst.COffset(b.Min.X+x, b.Min.Y+y)] = v },
+	)
+	return dst
+}
+
+func rgba64Chan(c color.RGBA64, i int) uint16 {
+	switch i {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	case 2:
+		return c.B
+	default:
+		return c.A
+	}
+}
+
+func setRGBA64Chan(c *color.RGBA64, i int, v uint16) {
+	switch i {
+	case 0:
+		c.R = v
+	case 1:
+		c.G = v
+	case 2:
+		c.B = v
+	default:
+		c.A = v
+	}
+}
+
+// convolveGeneric is the fallback path for image.Image implementations
+// that aren't one of the package's known concrete types. It filters
+// through img.At/dst.Set like the "default" case in resize().
+func convolveGeneric(img image.Image, k Kernel2D) image.Image {
+	b := img.Bounds()
+	temp := image.NewRGBA64(b)
+	convolveGenericRows(img, temp, b, k, true)
+	dst := image.NewRGBA64(b)
+	convolveGenericRows(temp, dst, b, k, false)
+	return dst
+}
+
+func convolveGenericRows(src image.Image, dst *image.RGBA64, b image.Rectangle, k Kernel2D, horizontal bool) {
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				var r, g, bl, a, wsum float64
+				for i, w := range k.Weights {
+					offset := i - k.Anchor
+					var sx, sy int
+					if horizontal {
+						sx, sy = clampInt(x+offset, b.Min.X, b.Max.X-1), y
+					} else {
+						sx, sy = x, clampInt(y+offset, b.Min.Y, b.Max.Y-1)
+					}
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += float64(cr) * w
+					g += float64(cg) * w
+					bl += float64(cb) * w
+					a += float64(ca) * w
+					wsum += w
+				}
+				if wsum == 0 {
+					wsum = 1
+				}
+				dst.SetRGBA64(x, y, color.RGBA64{
+					R: clampUint16(r / wsum),
+					G: clampUint16(g / wsum),
+					B: clampUint16(bl / wsum),
+					A: clampUint16(a / wsum),
+				})
+			}
+		}
+	})
+}
+
+// sharpen amplifies img's difference from blurred (itself always produced
+// by GaussianBlur(img, ...), so it shares img's concrete type) and
+// dispatches on that type the same way Convolve does, so UnsharpMask
+// preserves it instead of always widening to *image.RGBA64.
+func sharpen(img, blurred image.Image, amount float64, threshold uint32) image.Image {
+	switch src := img.(type) {
+	case *image.RGBA:
+		return sharpenRGBA(src, blurred.(*image.RGBA), amount, threshold)
+	case *image.YCbCr:
+		return sharpenYCbCr(src, blurred.(*image.YCbCr), amount, threshold)
+	case *image.Gray:
+		return sharpenGray(src, blurred.(*image.Gray), amount, threshold)
+	case *image.Gray16:
+		return sharpenGray16(src, blurred.(*image.Gray16), amount, threshold)
+	case *image.RGBA64:
+		return sharpenRGBA64(src, blurred.(*image.RGBA64), amount, threshold)
+	default:
+		return sharpenGeneric(img, blurred, amount, threshold)
+	}
+}
+
+func sharpenRGBA(img, blurred *image.RGBA, amount float64, threshold uint32) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, a := img.RGBAAt(x, y).RGBA()
+				br, bg, bb, _ := blurred.RGBAAt(x, y).RGBA()
+				dst.SetRGBA(x, y, color.RGBA{
+					R: uint8(sharpenChan(r, br, amount, threshold) >> 8),
+					G: uint8(sharpenChan(g, bg, amount, threshold) >> 8),
+					B: uint8(sharpenChan(bl, bb, amount, threshold) >> 8),
+					A: uint8(a >> 8),
+				})
+			}
+		}
+	})
+	return dst
+}
+
+func sharpenGray(img, blurred *image.Gray, amount float64, threshold uint32) *image.Gray {
+	b := img.Bounds()
+	dst := image.NewGray(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				v := uint32(img.GrayAt(x, y).Y) * 0x101
+				bv := uint32(blurred.GrayAt(x, y).Y) * 0x101
+				dst.SetGray(x, y, color.Gray{Y: uint8(sharpenChan(v, bv, amount, threshold) >> 8)})
+			}
+		}
+	})
+	return dst
+}
+
+func sharpenGray16(img, blurred *image.Gray16, amount float64, threshold uint32) *image.Gray16 {
+	b := img.Bounds()
+	dst := image.NewGray16(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				v := uint32(img.Gray16At(x, y).Y)
+				bv := uint32(blurred.Gray16At(x, y).Y)
+				dst.SetGray16(x, y, color.Gray16{Y: sharpenChan(v, bv, amount, threshold)})
+			}
+		}
+	})
+	return dst
+}
+
+func sharpenRGBA64(img, blurred *image.RGBA64, amount float64, threshold uint32) *image.RGBA64 {
+	b := img.Bounds()
+	dst := image.NewRGBA64(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := img.RGBA64At(x, y)
+				bc := blurred.RGBA64At(x, y)
+				dst.SetRGBA64(x, y, color.RGBA64{
+					R: sharpenChan(uint32(c.R), uint32(bc.R), amount, threshold),
+					G: sharpenChan(uint32(c.G), uint32(bc.G), amount, threshold),
+					B: sharpenChan(uint32(c.B), uint32(bc.B), amount, threshold),
+					A: c.A,
+				})
+			}
+		}
+	})
+	return dst
+}
+
+func sharpenYCbCr(img, blurred *image.YCbCr, amount float64, threshold uint32) *image.YCbCr {
+	b := img.Bounds()
+	dst := image.NewYCbCr(b, img.SubsampleRatio)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				yv, cb, cr := img.Y[img.YOffset(x, y)], img.Cb[img.COffset(x, y)], img.Cr[img.COffset(x, y)]
+				r8, g8, b8 := color.YCbCrToRGB(yv, cb, cr)
+
+				byv, bcb, bcr := blurred.Y[blurred.YOffset(x, y)], blurred.Cb[blurred.COffset(x, y)], blurred.Cr[blurred.COffset(x, y)]
+				br8, bg8, bb8 := color.YCbCrToRGB(byv, bcb, bcr)
+
+				nr := sharpenChan(uint32(r8)*0x101, uint32(br8)*0x101, amount, threshold)
+				ng := sharpenChan(uint32(g8)*0x101, uint32(bg8)*0x101, amount, threshold)
+				nb := sharpenChan(uint32(b8)*0x101, uint32(bb8)*0x101, amount, threshold)
+
+				ny, ncb, ncr := color.RGBToYCbCr(uint8(nr>>8), uint8(ng>>8), uint8(nb>>8))
+				dst.Y[dst.YOffset(x, y)] = ny
+				dst.Cb[dst.COffset(x, y)] = ncb
+				dst.Cr[dst.COffset(x, y)] = ncr
+			}
+		}
+	})
+	return dst
+}
+
+// sharpenGeneric is the fallback for image.Image implementations that
+// aren't one of the package's known concrete types; like convolveGeneric,
+// it widens to *image.RGBA64.
+func sharpenGeneric(img, blurred image.Image, amount float64, threshold uint32) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA64(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, a := img.At(x, y).RGBA()
+				br, bg, bb, _ := blurred.At(x, y).RGBA()
+				dst.SetRGBA64(x, y, color.RGBA64{
+					R: sharpenChan(r, br, amount, threshold),
+					G: sharpenChan(g, bg, amount, threshold),
+					B: sharpenChan(bl, bb, amount, threshold),
+					A: uint16(a),
+				})
+			}
+		}
+	})
+	return dst
+}
+
+func sharpenChan(c, blurred uint32, amount float64, threshold uint32) uint16 {
+	diff := int32(c) - int32(blurred)
+	if diff < 0 {
+		diff = -diff
+	}
+	if uint32(diff) <= threshold {
+		return uint16(c)
+	}
+	return clampUint16(float64(c) + (float64(c)-float64(blurred))*amount)
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,60 @@
+package resize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestBoxBlurFlatImage(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range src.Pix {
+		src.Pix[i] = 100
+	}
+
+	out := BoxBlur(src, 2)
+	dst, ok := out.(*image.Gray)
+	if !ok {
+		t.Fatalf("BoxBlur changed type: got %T, want *image.Gray", out)
+	}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if v := dst.GrayAt(x, y).Y; v != 100 {
+				t.Errorf("(%d,%d): got %d, want 100 (flat image should be unchanged)", x, y, v)
+			}
+		}
+	}
+}
+
+func TestBoxBlurSmoothsImpulse(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 9, 9))
+	src.SetGray(4, 4, color.Gray{Y: 255})
+
+	out := BoxBlur(src, 1).(*image.Gray)
+	if v := out.GrayAt(4, 4).Y; v == 255 || v == 0 {
+		t.Errorf("center of blurred impulse = %d, want a value between 0 and 255", v)
+	}
+	if v := out.GrayAt(0, 0).Y; v != 0 {
+		t.Errorf("corner far from impulse = %d, want 0", v)
+	}
+}
+
+func TestConvolvePreservesYCbCrType(t *testing.T) {
+	src := image.NewYCbCr(image.Rect(0, 0, 8, 8), image.YCbCrSubsampleRatio420)
+	for i := range src.Y {
+		src.Y[i] = 128
+	}
+	for i := range src.Cb {
+		src.Cb[i] = 128
+		src.Cr[i] = 128
+	}
+
+	out := BoxBlur(src, 1)
+	dst, ok := out.(*image.YCbCr)
+	if !ok {
+		t.Fatalf("BoxBlur changed type: got %T, want *image.YCbCr", out)
+	}
+	if dst.SubsampleRatio != src.SubsampleRatio {
+		t.Errorf("got SubsampleRatio %v, want %v", dst.SubsampleRatio, src.SubsampleRatio)
+	}
+}
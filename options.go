@@ -0,0 +1,291 @@
+package resize
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+// GammaMode selects the color space ResizeOpts filters in.
+type GammaMode int
+
+const (
+	// GammaNone filters in the image's encoded color space. This is
+	// Resize's historical behavior, and is wrong for sRGB images, but
+	// is kept as the default for backwards compatibility.
+	GammaNone GammaMode = iota
+	// GammaSRGB converts samples from sRGB to linear light before
+	// filtering and back to sRGB afterwards.
+	GammaSRGB
+	// GammaCustom converts samples using Options.Decode and Options.Encode.
+	GammaCustom
+)
+
+// Options configures ResizeOpts.
+type Options struct {
+	Interp InterpolationFunction
+
+	// Blur scales the support of Interp's kernel; values below 1.0
+	// sharpen, values above 1.0 soften. A zero value defaults to 1.0,
+	// matching Resize.
+	Blur float64
+
+	// Gamma selects the color space filtering happens in. The zero
+	// value, GammaNone, matches Resize's historical behavior.
+	Gamma GammaMode
+
+	// Decode and Encode convert a channel value in [0, 1] to/from
+	// linear light. They are only used when Gamma is GammaCustom.
+	Decode func(float64) float64
+	Encode func(float64) float64
+
+	// PremultipliedAlpha, if true, divides out alpha before filtering
+	// and re-multiplies it on write, avoiding dark halos around
+	// transparent edges.
+	PremultipliedAlpha bool
+}
+
+// ResizeOpts scales img like Resize, but lets the caller control the blur
+// factor, the color space filtering happens in, and whether alpha is
+// unpremultiplied before filtering.
+//
+// When gamma correction or unpremultiplication is requested, the decoded
+// values are carried through filtering in a widened *image.RGBA64 buffer
+// rather than narrowed back to img's native bit depth first: sRGB's gamma
+// curve compresses many encoded shadow levels into a tiny linear range, so
+// truncating to (for example) 8 bits before the weighted sum ever runs
+// would reintroduce the banding gamma-correct resizing is meant to avoid.
+// The result is narrowed back to img's concrete type only at the end when
+// img is an *image.RGBA, *image.Gray, *image.Gray16 or *image.YCbCr; any
+// other type comes back as an *image.RGBA64.
+func ResizeOpts(width, height uint, img image.Image, opts Options) (image.Image, error) {
+	if opts.Gamma == GammaCustom && (opts.Decode == nil || opts.Encode == nil) {
+		return nil, errors.New("resize: GammaCustom requires both Decode and Encode")
+	}
+
+	blur := opts.Blur
+	if blur == 0 {
+		blur = 1.0
+	}
+	decode, encode := gammaFuncs(opts, isHighPrecision(img))
+
+	prepared := img
+	if opts.PremultipliedAlpha || decode != nil {
+		prepared = mapToRGBA64(img, func(r, g, b, a uint32) (uint16, uint16, uint16, uint16) {
+			if opts.PremultipliedAlpha {
+				r, g, b = unpremultiplyChan(r, a), unpremultiplyChan(g, a), unpremultiplyChan(b, a)
+			}
+			if decode != nil {
+				r, g, b = applyGamma(r, decode), applyGamma(g, decode), applyGamma(b, decode)
+			}
+			return uint16(r), uint16(g), uint16(b), uint16(a)
+		})
+	}
+
+	width, height, scaleX, scaleY := resizeDims(width, height, prepared)
+	var result image.Image
+	var err error
+	if opts.Interp == NearestNeighbor {
+		result, err = resizeNearest(width, height, scaleX, scaleY, prepared, opts.Interp, blur)
+	} else {
+		result, err = resize(width, height, scaleX, scaleY, prepared, opts.Interp, blur)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.PremultipliedAlpha || encode != nil {
+		// prepared was widened above whenever this branch can run, so
+		// resize()/resizeNearest() preserved that and result is RGBA64.
+		result = mapFromRGBA64(result.(*image.RGBA64), img, func(r, g, b, a uint32) (uint16, uint16, uint16, uint16) {
+			if encode != nil {
+				r, g, b = applyGamma(r, encode), applyGamma(g, encode), applyGamma(b, encode)
+			}
+			if opts.PremultipliedAlpha {
+				r, g, b = premultiplyChan(r, a), premultiplyChan(g, a), premultiplyChan(b, a)
+			}
+			return uint16(r), uint16(g), uint16(b), uint16(a)
+		})
+	}
+	return result, nil
+}
+
+// isHighPrecision reports whether img is an *image.RGBA64 or an
+// *image.Gray16, the 16-bit types for which gammaFuncs avoids
+// srgbDecodeLUT's 256-entry table, which would throw away real precision.
+func isHighPrecision(img image.Image) bool {
+	switch img.(type) {
+	case *image.RGBA64, *image.Gray16:
+		return true
+	default:
+		return false
+	}
+}
+
+func gammaFuncs(opts Options, highPrecision bool) (decode, encode func(float64) float64) {
+	switch opts.Gamma {
+	case GammaSRGB:
+		if highPrecision {
+			return srgbDecode, srgbEncode
+		}
+		return srgbDecodeLUT, srgbEncode
+	case GammaCustom:
+		return opts.Decode, opts.Encode
+	default:
+		return nil, nil
+	}
+}
+
+func applyGamma(c uint32, f func(float64) float64) uint32 {
+	return uint32(clampUint16(f(float64(c)/0xffff) * 0xffff))
+}
+
+func unpremultiplyChan(c, a uint32) uint32 {
+	if a == 0 {
+		return 0
+	}
+	return uint32(clampUint16(float64(c) * 0xffff / float64(a)))
+}
+
+func premultiplyChan(c, a uint32) uint32 {
+	return c * a / 0xffff
+}
+
+// srgbLUT is a 256-entry precomputed table from an 8-bit sRGB value to
+// its linear-light equivalent in [0, 1].
+var srgbLUT [256]float64
+
+func init() {
+	for i := range srgbLUT {
+		srgbLUT[i] = srgbDecode(float64(i) / 255)
+	}
+}
+
+// srgbDecodeLUT decodes via the 256-entry LUT, which is accurate enough
+// for the 8-bit resample paths; gammaFuncs picks srgbDecode directly
+// instead for *image.RGBA64/*image.Gray16 sources, where the LUT's
+// resolution would throw away real precision.
+func srgbDecodeLUT(v float64) float64 {
+	i := int(v*255 + 0.5)
+	if i < 0 {
+		i = 0
+	} else if i > 255 {
+		i = 255
+	}
+	return srgbLUT[i]
+}
+
+func srgbDecode(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func srgbEncode(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// mapToRGBA64 applies f to every pixel of img in parallel (the same
+// row-based goroutine fan-out resize() uses), always widening to
+// *image.RGBA64 regardless of img's concrete type. ResizeOpts uses this
+// for its gamma-decode pass so linear-light values are carried at full
+// precision into filtering rather than truncated to img's native depth.
+func mapToRGBA64(img image.Image, f func(r, g, b, a uint32) (uint16, uint16, uint16, uint16)) *image.RGBA64 {
+	b := img.Bounds()
+	dst := image.NewRGBA64(b)
+	parallelRows(b.Dy(), func(y0, y1 int) {
+		for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, a := img.At(x, y).RGBA()
+				nr, ng, nb, na := f(r, g, bl, a)
+				dst.SetRGBA64(x, y, color.RGBA64{R: nr, G: ng, B: nb, A: na})
+			}
+		}
+	})
+	return dst
+}
+
+// mapFromRGBA64 applies f to every pixel of src and narrows the result
+// into a freshly allocated image of like's concrete type, so ResizeOpts'
+// gamma-encode pass can undo the widening mapToRGBA64 did while still
+// returning the type the caller passed in. Only *image.RGBA, *image.Gray,
+// *image.Gray16 and *image.YCbCr are narrowed; for any other type the
+// result is an *image.RGBA64.
+func mapFromRGBA64(src *image.RGBA64, like image.Image, f func(r, g, b, a uint32) (uint16, uint16, uint16, uint16)) image.Image {
+	b := src.Bounds()
+	switch like.(type) {
+	case *image.RGBA:
+		dst := image.NewRGBA(b)
+		parallelRows(b.Dy(), func(y0, y1 int) {
+			for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					c := src.RGBA64At(x, y)
+					nr, ng, nb, na := f(uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A))
+					dst.SetRGBA(x, y, color.RGBA{R: uint8(nr >> 8), G: uint8(ng >> 8), B: uint8(nb >> 8), A: uint8(na >> 8)})
+				}
+			}
+		})
+		return dst
+
+	case *image.Gray:
+		dst := image.NewGray(b)
+		parallelRows(b.Dy(), func(y0, y1 int) {
+			for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					c := src.RGBA64At(x, y)
+					nr, _, _, _ := f(uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A))
+					dst.SetGray(x, y, color.Gray{Y: uint8(nr >> 8)})
+				}
+			}
+		})
+		return dst
+
+	case *image.Gray16:
+		dst := image.NewGray16(b)
+		parallelRows(b.Dy(), func(y0, y1 int) {
+			for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					c := src.RGBA64At(x, y)
+					nr, _, _, _ := f(uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A))
+					dst.SetGray16(x, y, color.Gray16{Y: nr})
+				}
+			}
+		})
+		return dst
+
+	case *image.YCbCr:
+		ratio := like.(*image.YCbCr).SubsampleRatio
+		dst := image.NewYCbCr(b, ratio)
+		parallelRows(b.Dy(), func(y0, y1 int) {
+			for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					c := src.RGBA64At(x, y)
+					nr, ng, nb, _ := f(uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A))
+					ny, ncb, ncr := color.RGBToYCbCr(uint8(nr>>8), uint8(ng>>8), uint8(nb>>8))
+					dst.Y[dst.YOffset(x, y)] = ny
+					dst.Cb[dst.COffset(x, y)] = ncb
+					dst.Cr[dst.COffset(x, y)] = ncr
+				}
+			}
+		})
+		return dst
+
+	default: // *image.RGBA64, and anything else ResizeOpts was given
+		dst := image.NewRGBA64(b)
+		parallelRows(b.Dy(), func(y0, y1 int) {
+			for y := b.Min.Y + y0; y < b.Min.Y+y1; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					c := src.RGBA64At(x, y)
+					nr, ng, nb, na := f(uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A))
+					dst.SetRGBA64(x, y, color.RGBA64{R: nr, G: ng, B: nb, A: na})
+				}
+			}
+		})
+		return dst
+	}
+}
@@ -0,0 +1,58 @@
+package resize
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func newGradientRGBA(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x * 40), G: uint8(y * 40), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func TestSRGBRoundTrip(t *testing.T) {
+	for _, v := range []float64{0, 0.01, 0.2, 0.5, 0.77, 1} {
+		linear := srgbDecode(v)
+		got := srgbEncode(linear)
+		if math.Abs(got-v) > 1e-9 {
+			t.Errorf("srgbEncode(srgbDecode(%v)) = %v, want %v", v, got, v)
+		}
+	}
+}
+
+func TestSRGBDecodeKnownValues(t *testing.T) {
+	// 0.5 encoded sRGB decodes to roughly 0.214 linear.
+	if got := srgbDecode(0.5); math.Abs(got-0.2140) > 1e-3 {
+		t.Errorf("srgbDecode(0.5) = %v, want ~0.214", got)
+	}
+	// The LUT rounds 0.5 to entry 128 (128/255 ~= 0.502), which decodes to
+	// roughly 0.216 linear.
+	if got := srgbDecodeLUT(0.5); math.Abs(got-0.2159) > 1e-3 {
+		t.Errorf("srgbDecodeLUT(0.5) = %v, want ~0.216", got)
+	}
+}
+
+func TestResizeOptsGammaCustomRequiresFuncs(t *testing.T) {
+	src := newGradientRGBA(4, 4)
+	if _, err := ResizeOpts(2, 2, src, Options{Interp: Bilinear, Gamma: GammaCustom}); err == nil {
+		t.Fatal("expected an error when GammaCustom is set without Decode/Encode")
+	}
+}
+
+func TestResizeOptsPreservesType(t *testing.T) {
+	src := newGradientRGBA(4, 4)
+	out, err := ResizeOpts(2, 2, src, Options{Interp: Bilinear, Gamma: GammaSRGB})
+	if err != nil {
+		t.Fatalf("ResizeOpts: %v", err)
+	}
+	if _, ok := out.(*image.RGBA); !ok {
+		t.Errorf("ResizeOpts changed type: got %T, want *image.RGBA", out)
+	}
+}
@@ -93,6 +93,16 @@ func Resize(width, height uint, img image.Image, interp InterpolationFunction) i
 
 // ResizeSafe scales an image using Resize, but will return an error, if any.
 func ResizeSafe(width, height uint, img image.Image, interp InterpolationFunction) (image.Image, error) {
+	width, height, scaleX, scaleY := resizeDims(width, height, img)
+	if interp == NearestNeighbor {
+		return resizeNearest(width, height, scaleX, scaleY, img, interp, blur)
+	}
+	return resize(width, height, scaleX, scaleY, img, interp, blur)
+}
+
+// resizeDims computes the destination dimensions (filling in any 0
+// dimension from the source aspect ratio) and the resulting scale factors.
+func resizeDims(width, height uint, img image.Image) (uint, uint, float64, float64) {
 	scaleX, scaleY := calcFactors(width, height, float64(img.Bounds().Dx()), float64(img.Bounds().Dy()))
 	if width == 0 {
 		width = uint(0.7 + float64(img.Bounds().Dx())/scaleX)
@@ -100,13 +110,10 @@ func ResizeSafe(width, height uint, img image.Image, interp InterpolationFunctio
 	if height == 0 {
 		height = uint(0.7 + float64(img.Bounds().Dy())/scaleY)
 	}
-	if interp == NearestNeighbor {
-		return resizeNearest(width, height, scaleX, scaleY, img, interp)
-	}
-	return resize(width, height, scaleX, scaleY, img, interp)
+	return width, height, scaleX, scaleY
 }
 
-func resize(width, height uint, scaleX, scaleY float64, img image.Image, interp InterpolationFunction) (image.Image, error) {
+func resize(width, height uint, scaleX, scaleY float64, img image.Image, interp InterpolationFunction, blur float64) (image.Image, error) {
 	taps, kernel := interp.kernel()
 	cpus := runtime.NumCPU()
 	done := make(chan error, cpus)
@@ -322,7 +329,7 @@ func resize(width, height uint, scaleX, scaleY float64, img image.Image, interp
 	}
 }
 
-func resizeNearest(width, height uint, scaleX, scaleY float64, img image.Image, interp InterpolationFunction) (image.Image, error) {
+func resizeNearest(width, height uint, scaleX, scaleY float64, img image.Image, interp InterpolationFunction, blur float64) (image.Image, error) {
 	taps, _ := interp.kernel()
 	cpus := runtime.NumCPU()
 	done := make(chan error, cpus)
@@ -0,0 +1,162 @@
+package resize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// FitMode selects how Fit maps img into a width x height box.
+type FitMode int
+
+const (
+	// FitInside scales img to fit entirely within the box, preserving
+	// aspect ratio, and never upscales beyond the box.
+	FitInside FitMode = iota
+	// FitCover scales img to fill the box, preserving aspect ratio, and
+	// crops whatever overflows.
+	FitCover
+	// FitStretch scales img to exactly width x height, ignoring aspect
+	// ratio. This is the behavior of Resize.
+	FitStretch
+	// FitPad scales img to fit entirely within the box, preserving
+	// aspect ratio, and letterboxes the remainder with FitOptions.Background.
+	FitPad
+)
+
+// Anchor selects which part of an image Fit keeps when FitCover crops it
+// or where FitPad places it within the padded box.
+type Anchor int
+
+const (
+	// AnchorCenter centers the image. It is the zero value.
+	AnchorCenter Anchor = iota
+	AnchorTopLeft
+	AnchorBottomRight
+)
+
+// FitOptions customizes the cropping and padding behavior of Fit.
+type FitOptions struct {
+	// Anchor selects the crop or pad origin. Ignored if AnchorPoint is set.
+	Anchor Anchor
+	// AnchorPoint, if non-nil, overrides Anchor with an explicit origin
+	// in the relevant image's coordinate space.
+	AnchorPoint *image.Point
+	// Background fills the letterbox border for FitPad. The zero value
+	// is fully transparent.
+	Background color.Color
+}
+
+// Thumbnail scales img down to fit within maxWidth x maxHeight, preserving
+// aspect ratio and never upscaling. It is equivalent to
+// Fit(maxWidth, maxHeight, img, FitInside, interp), and likewise returns
+// nil if maxWidth or maxHeight is 0.
+func Thumbnail(maxWidth, maxHeight uint, img image.Image, interp InterpolationFunction) image.Image {
+	return Fit(maxWidth, maxHeight, img, FitInside, interp)
+}
+
+// Fit scales img into a width x height box according to mode. opts is
+// optional and controls the crop anchor (FitCover) or background
+// (FitPad); the zero value anchors on center with a transparent background.
+//
+// Unlike Resize, FitCover, FitPad and FitInside do not support a zero
+// width or height to mean "preserve aspect ratio automatically" — the box
+// itself defines the aspect ratio they fit or crop to, so Fit returns nil
+// for those modes if width or height is 0. FitStretch has no such
+// restriction and defers to Resize's own zero-dimension behavior.
+func Fit(width, height uint, img image.Image, mode FitMode, interp InterpolationFunction, opts ...FitOptions) image.Image {
+	var o FitOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	if (width == 0 || height == 0) && mode != FitStretch {
+		return nil
+	}
+
+	switch mode {
+	case FitCover:
+		return Resize(width, height, cropToAspect(img, width, height, o), interp)
+
+	case FitPad:
+		w, h := fitInside(width, height, img)
+		return padTo(Resize(w, h, img, interp), width, height, o)
+
+	case FitInside:
+		w, h := fitInside(width, height, img)
+		return Resize(w, h, img, interp)
+
+	default: // FitStretch
+		return Resize(width, height, img, interp)
+	}
+}
+
+func fitInside(width, height uint, img image.Image) (uint, uint) {
+	b := img.Bounds()
+	sw, sh := float64(b.Dx()), float64(b.Dy())
+	scale := math.Min(float64(width)/sw, float64(height)/sh)
+	if scale > 1 {
+		scale = 1
+	}
+	return uint(sw*scale + 0.5), uint(sh*scale + 0.5)
+}
+
+// cropToAspect returns the largest box with the width:height aspect ratio
+// that fits within img, anchored per o. When img supports SubImage the
+// crop is taken on the concrete type so Resize's fast paths still fire;
+// otherwise the cropped box is copied into a new *image.RGBA64.
+func cropToAspect(img image.Image, width, height uint, o FitOptions) image.Image {
+	b := img.Bounds()
+	targetAspect := float64(width) / float64(height)
+	srcAspect := float64(b.Dx()) / float64(b.Dy())
+
+	var cw, ch int
+	if srcAspect > targetAspect {
+		ch = b.Dy()
+		cw = int(float64(ch)*targetAspect + 0.5)
+	} else {
+		cw = b.Dx()
+		ch = int(float64(cw)/targetAspect + 0.5)
+	}
+
+	origin := anchorPoint(b, cw, ch, o)
+	rect := image.Rect(origin.X, origin.Y, origin.X+cw, origin.Y+ch)
+
+	sub, ok := img.(imageWithSubImage)
+	if !ok {
+		dst := image.NewRGBA64(image.Rect(0, 0, cw, ch))
+		draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+		return dst
+	}
+	return sub.SubImage(rect)
+}
+
+func padTo(img image.Image, width, height uint, o FitOptions) image.Image {
+	bg := o.Background
+	if bg == nil {
+		bg = color.Transparent
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	b := img.Bounds()
+	origin := anchorPoint(dst.Bounds(), b.Dx(), b.Dy(), o)
+	target := image.Rect(origin.X, origin.Y, origin.X+b.Dx(), origin.Y+b.Dy())
+	draw.Draw(dst, target, img, b.Min, draw.Over)
+	return dst
+}
+
+func anchorPoint(b image.Rectangle, w, h int, o FitOptions) image.Point {
+	if o.AnchorPoint != nil {
+		return *o.AnchorPoint
+	}
+	switch o.Anchor {
+	case AnchorTopLeft:
+		return b.Min
+	case AnchorBottomRight:
+		return image.Pt(b.Max.X-w, b.Max.Y-h)
+	default: // AnchorCenter
+		return image.Pt(b.Min.X+(b.Dx()-w)/2, b.Min.Y+(b.Dy()-h)/2)
+	}
+}
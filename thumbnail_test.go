@@ -0,0 +1,59 @@
+package resize
+
+import (
+	"image"
+	"testing"
+)
+
+func TestFitDimensions(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 200, 100))
+
+	cases := []struct {
+		mode FitMode
+		w, h uint
+	}{
+		{FitInside, 50, 50},
+		{FitCover, 50, 50},
+		{FitStretch, 50, 50},
+		{FitPad, 50, 50},
+	}
+
+	for _, c := range cases {
+		img := Fit(c.w, c.h, src, c.mode, Bilinear)
+		if img == nil {
+			t.Fatalf("mode %v: Fit returned nil", c.mode)
+		}
+		b := img.Bounds()
+		switch c.mode {
+		case FitCover, FitStretch, FitPad:
+			if uint(b.Dx()) != c.w || uint(b.Dy()) != c.h {
+				t.Errorf("mode %v: got %dx%d, want %dx%d", c.mode, b.Dx(), b.Dy(), c.w, c.h)
+			}
+		case FitInside:
+			if uint(b.Dx()) > c.w || uint(b.Dy()) > c.h {
+				t.Errorf("mode %v: got %dx%d, want at most %dx%d", c.mode, b.Dx(), b.Dy(), c.w, c.h)
+			}
+		}
+	}
+}
+
+func TestFitZeroDimension(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for _, mode := range []FitMode{FitInside, FitCover, FitPad} {
+		if img := Fit(0, 10, src, mode, Bilinear); img != nil {
+			t.Errorf("mode %v: width 0 should return nil, got %v", mode, img)
+		}
+		if img := Fit(10, 0, src, mode, Bilinear); img != nil {
+			t.Errorf("mode %v: height 0 should return nil, got %v", mode, img)
+		}
+	}
+}
+
+func TestThumbnailNoUpscale(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	img := Thumbnail(100, 100, src, Bilinear)
+	b := img.Bounds()
+	if b.Dx() != 20 || b.Dy() != 10 {
+		t.Errorf("got %dx%d, want 20x10 (no upscale)", b.Dx(), b.Dy())
+	}
+}